@@ -12,82 +12,248 @@
 package debounce
 
 import (
-	"sync"
+	"context"
+	"errors"
 	"time"
 )
 
-type Manager struct {
-	cfg     Config
-	buffers map[string]*buffer
-	mu      sync.Mutex
+// ErrClosed is returned by Run once the Manager has been closed. After Close,
+// Add discards items and returns NoOverflow.
+var ErrClosed = errors.New("debounce: manager is closed")
+
+// blockAddPollInterval is how often Add re-checks capacity under the
+// BlockAdd overflow policy.
+const blockAddPollInterval = 5 * time.Millisecond
+
+// OverflowPolicy controls what Add does when MaxBufferedItemsPerKey or
+// MaxBufferedItemsTotal is reached.
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest buffered item for the key to make room.
+	// If the key has no buffered items, the new item is buffered anyway, even
+	// when MaxBufferedItemsTotal has been reached.
+	DropOldest OverflowPolicy = iota
+	// DropNewest discards the item passed to Add instead of buffering it.
+	DropNewest
+	// BlockAdd makes Add poll until capacity frees up.
+	BlockAdd
+	// FlushImmediately buffers the item and flushes the key right away.
+	FlushImmediately
+)
+
+// OverflowAction reports what Add did in response to an overflow, so
+// callers can react (e.g. record metrics or apply backpressure).
+type OverflowAction int
+
+const (
+	// NoOverflow means the item was buffered normally.
+	NoOverflow OverflowAction = iota
+	OverflowDroppedOldest
+	OverflowDroppedNewest
+	OverflowBlocked
+	OverflowFlushed
+)
+
+// Flusher is an alternative to Config.SendFunc for routing a flushed batch
+// to a sink. Set at most one of SendFunc or Flusher; if both are set,
+// SendFunc takes precedence. See the debounce/flusher package for ready-made
+// implementations (HTTP, file, channel, multi-sink fan-out).
+type Flusher interface {
+	Flush(ctx context.Context, key string, batch []interface{}) error
+}
+
+// Hooks, if set, let callers observe Manager's internal lifecycle events for
+// logging, tracing, or custom metrics. Every hook is optional and is invoked
+// synchronously on whichever goroutine triggered the event; hooks should
+// return quickly and must not call back into the Manager they're attached
+// to.
+type Hooks struct {
+	// OnAdd fires after Add buffers an item, reporting the key's buffered
+	// item count afterward.
+	OnAdd func(key string, bufferedCount int)
+	// OnFlushScheduled fires when a new flush timer is armed for key.
+	OnFlushScheduled func(key string, in time.Duration)
+	// OnFlushStart fires just before a batch is handed to SendFunc/Flusher.
+	OnFlushStart func(key string, batchSize int)
+	// OnFlushDone fires after a dispatch attempt completes, successful or
+	// not. err is the error from that attempt, even if a retry follows.
+	OnFlushDone func(key string, batchSize int, dur time.Duration, err error)
+	// OnRateLimited fires when a retry is stretched to honor
+	// MinIntervalBetweenFlushes.
+	OnRateLimited func(key string, waitFor time.Duration)
+	// OnEvict fires when a key's buffer is removed by MaxKeys or
+	// IdleEviction.
+	OnEvict func(key string)
+}
+
+// MetricsRecorder is a minimal metrics sink Manager reports standard
+// counters, gauges, and histograms through, so callers can wire it to
+// Prometheus, OpenTelemetry, or structured logging without writing their own
+// Hooks.
+type MetricsRecorder interface {
+	IncCounter(name string, labels map[string]string)
+	ObserveHistogram(name string, value float64, labels map[string]string)
+	SetGauge(name string, value float64, labels map[string]string)
+}
+
+// KeyStats summarizes a single key's buffering state, as returned by Stats.
+type KeyStats struct {
+	// Buffered is the number of items currently buffered for the key.
+	Buffered int
+	// LastFlushTime is when the key was last successfully flushed. Zero if
+	// never flushed.
+	LastFlushTime time.Time
+	// FlushedBatches is the lifetime count of batches successfully sent
+	// for the key. It is not reset by eviction.
+	FlushedBatches int64
+	// FlushedItems is the lifetime count of items successfully sent for
+	// the key. It is not reset by eviction.
+	FlushedItems int64
 }
 
 type Config struct {
 	FlushAfter                time.Duration
 	MinIntervalBetweenFlushes time.Duration
-	SendFunc                  func(string, []interface{})
+	SendFunc                  func(ctx context.Context, key string, batch []interface{}) error
+	// Flusher is used in place of SendFunc when SendFunc is nil.
+	Flusher Flusher
+
+	// DrainTimeout bounds how long Close waits for pending flushes and
+	// in-flight SendFunc calls before giving up. Zero means wait for ctx
+	// passed to Close alone.
+	DrainTimeout time.Duration
+
+	// ErrorHandler is invoked once a batch has exhausted MaxAttempts. It
+	// lets callers route the batch to a dead-letter buffer, log it, or
+	// record metrics. Its return value is ignored: once ErrorHandler itself
+	// has run, there is nowhere further to report a failure.
+	ErrorHandler func(key string, batch []interface{}, err error) error
+
+	// MaxAttempts is the total number of times SendFunc is called for a given
+	// batch before giving up. Values <= 1 mean no retries.
+	MaxAttempts int
+	// RetryBackoff is the base delay before the first retry.
+	RetryBackoff time.Duration
+	// BackoffMultiplier grows RetryBackoff exponentially per attempt when > 1.
+	BackoffMultiplier float64
+	// BackoffJitter is a fraction (0-1) of the computed backoff to randomize
+	// by, to avoid retry storms across keys.
+	BackoffJitter float64
+
+	// Requeue, when set, prepends a batch that exhausted MaxAttempts back
+	// into its key's buffer instead of dropping it, so it goes out with the
+	// next flush.
+	Requeue bool
+	// MaxRequeuedItems caps how many items from a failed batch are requeued,
+	// keeping the oldest items at the front. Zero means no cap.
+	MaxRequeuedItems int
+
+	// FlushLength, if set, flushes a key's buffer as soon as it accumulates
+	// this many items, instead of waiting for FlushAfter. Zero disables the
+	// size-based trigger.
+	FlushLength int
+
+	// MaxKeys caps how many distinct keys may have a buffer at once. When
+	// adding an item for a new key would exceed it, the least-recently-used
+	// key is evicted (flushing it first if it has buffered items). Zero
+	// means unbounded.
+	MaxKeys int
+	// IdleEviction removes a key's buffer once it has been empty for at
+	// least this long, via a background janitor started by Run. Zero
+	// disables idle eviction.
+	IdleEviction time.Duration
+
+	// MaxBufferedItemsPerKey caps how many items a single key's buffer may
+	// hold. Zero means unbounded.
+	MaxBufferedItemsPerKey int
+	// MaxBufferedItemsTotal caps how many items may be buffered across all
+	// keys combined. Zero means unbounded.
+	MaxBufferedItemsTotal int
+	// OverflowPolicy decides what Add does once either cap above is hit.
+	OverflowPolicy OverflowPolicy
+
+	// Hooks observes Manager's internal lifecycle events. See Hooks.
+	Hooks Hooks
+	// Metrics, if set, receives standard counters/gauges/histograms for
+	// add, flush start/done, rate-limit and eviction events alongside Hooks.
+	// Flush scheduling is reported only through Hooks.OnFlushScheduled.
+	Metrics MetricsRecorder
 }
 
-type buffer struct {
-	mu             sync.Mutex
-	items          []interface{}
-	timer          *time.Timer
-	lastFlushTime  time.Time
-	flushScheduled bool
+// Manager batches interface{} items per key. It is the original,
+// backward-compatible API; new code that knows its item type at compile
+// time should prefer TypedManager[T] directly. Manager is a thin wrapper
+// around a TypedManager[interface{}] so the locking/retry/eviction logic
+// lives in exactly one place.
+type Manager struct {
+	typed *TypedManager[interface{}]
 }
 
 func NewManager(cfg Config) *Manager {
-	return &Manager{
-		cfg:     cfg,
-		buffers: make(map[string]*buffer),
-	}
+	return &Manager{typed: NewTypedManager(TypedConfig[interface{}]{
+		FlushAfter:                cfg.FlushAfter,
+		MinIntervalBetweenFlushes: cfg.MinIntervalBetweenFlushes,
+		SendFunc:                  dispatchFunc(cfg),
+		DrainTimeout:              cfg.DrainTimeout,
+		ErrorHandler:              cfg.ErrorHandler,
+		MaxAttempts:               cfg.MaxAttempts,
+		RetryBackoff:              cfg.RetryBackoff,
+		BackoffMultiplier:         cfg.BackoffMultiplier,
+		BackoffJitter:             cfg.BackoffJitter,
+		Requeue:                   cfg.Requeue,
+		MaxRequeuedItems:          cfg.MaxRequeuedItems,
+		FlushLength:               cfg.FlushLength,
+		MaxKeys:                   cfg.MaxKeys,
+		IdleEviction:              cfg.IdleEviction,
+		MaxBufferedItemsPerKey:    cfg.MaxBufferedItemsPerKey,
+		MaxBufferedItemsTotal:     cfg.MaxBufferedItemsTotal,
+		OverflowPolicy:            cfg.OverflowPolicy,
+		Hooks:                     cfg.Hooks,
+		Metrics:                   cfg.Metrics,
+	})}
 }
 
-func (m *Manager) Add(key string, item interface{}) {
-	m.mu.Lock()
-	b, exists := m.buffers[key]
-	if !exists {
-		b = &buffer{}
-		m.buffers[key] = b
-	}
-	m.mu.Unlock()
-
-	b.mu.Lock()
-	b.items = append(b.items, item)
-	if !b.flushScheduled {
-		b.flushScheduled = true
-		b.timer = time.AfterFunc(m.cfg.FlushAfter, func() {
-			m.flush(key)
-		})
+// dispatchFunc adapts Config's SendFunc/Flusher pair into the single
+// SendFunc TypedConfig expects, preferring SendFunc when both are set.
+func dispatchFunc(cfg Config) func(ctx context.Context, key string, batch []interface{}) error {
+	return func(ctx context.Context, key string, batch []interface{}) error {
+		if cfg.SendFunc != nil {
+			return cfg.SendFunc(ctx, key, batch)
+		}
+		if cfg.Flusher != nil {
+			return cfg.Flusher.Flush(ctx, key, batch)
+		}
+		return nil
 	}
-	b.mu.Unlock()
 }
 
-func (m *Manager) flush(key string) {
-	m.mu.Lock()
-	b, exists := m.buffers[key]
-	if !exists {
-		m.mu.Unlock()
-		return
-	}
-	m.mu.Unlock()
-
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	now := time.Now()
-	if !b.lastFlushTime.IsZero() && now.Sub(b.lastFlushTime) < m.cfg.MinIntervalBetweenFlushes {
-		delay := m.cfg.MinIntervalBetweenFlushes - now.Sub(b.lastFlushTime)
-		b.timer = time.AfterFunc(delay, func() {
-			m.flush(key)
-		})
-		return
-	}
+// Run starts the Manager's lifecycle: ctx is propagated to every SendFunc
+// call until the Manager is closed, and the idle-eviction janitor (if
+// IdleEviction is set) starts running. Run does not block; callers
+// typically defer a call to Close for graceful shutdown.
+func (m *Manager) Run(ctx context.Context) error {
+	return m.typed.Run(ctx)
+}
+
+// Close stops all pending flush timers, flushes every non-empty buffer
+// synchronously, and waits for outstanding SendFunc calls, including retries
+// already scheduled, to finish. It
+// returns once draining completes or ctx (bounded by DrainTimeout, if set)
+// expires, whichever comes first.
+func (m *Manager) Close(ctx context.Context) error {
+	return m.typed.Close(ctx)
+}
 
-	batch := b.items
-	b.items = nil
-	b.lastFlushTime = now
-	b.flushScheduled = false
+// Add buffers item under key, creating the key's buffer on first use. It
+// returns the OverflowAction taken, if MaxBufferedItemsPerKey or
+// MaxBufferedItemsTotal was reached.
+func (m *Manager) Add(key string, item interface{}) OverflowAction {
+	return m.typed.Add(key, item)
+}
 
-	go m.cfg.SendFunc(key, batch)
+// Stats returns a snapshot of KeyStats for every key Manager has ever seen.
+// The returned map is a copy, safe to read without further locking.
+func (m *Manager) Stats() map[string]KeyStats {
+	return m.typed.Stats()
 }
@@ -1,6 +1,8 @@
 package debounce
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
@@ -19,12 +21,13 @@ func TestPerDeviceBufferingAndSmoothing(t *testing.T) {
 	mgr := NewManager(Config{
 		FlushAfter:                2 * time.Second,
 		MinIntervalBetweenFlushes: 200 * time.Millisecond,
-		SendFunc: func(key string, batch []interface{}) {
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
 			now := time.Now()
 			mu.Lock()
 			sentTimestamps[key] = append(sentTimestamps[key], now)
 			mu.Unlock()
 			fmt.Printf("[Send] %s: %d items\n", key, len(batch))
+			return nil
 		},
 	})
 
@@ -48,5 +51,211 @@ func TestPerDeviceBufferingAndSmoothing(t *testing.T) {
 	}
 
 	wg.Wait()
-	time.Sleep(3 * time.Second) // allow final flushes
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mgr.Close(closeCtx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestRetryBackoffAndDeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	sendErr := errors.New("downstream unavailable")
+
+	type deadLetter struct {
+		key   string
+		batch []interface{}
+		err   error
+	}
+	errCh := make(chan deadLetter, 1)
+
+	mgr := NewManager(Config{
+		FlushAfter:   time.Hour,
+		FlushLength:  2,
+		MaxAttempts:  3,
+		RetryBackoff: 10 * time.Millisecond,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			return sendErr
+		},
+		ErrorHandler: func(key string, batch []interface{}, err error) error {
+			errCh <- deadLetter{key, batch, err}
+			return nil
+		},
+	})
+
+	mgr.Add("dev-a", "x")
+	mgr.Add("dev-a", "y") // FlushLength=2 triggers an immediate flush
+
+	select {
+	case got := <-errCh:
+		if got.key != "dev-a" {
+			t.Fatalf("expected key dev-a, got %s", got.key)
+		}
+		if len(got.batch) != 2 {
+			t.Fatalf("expected batch of 2 items, got %d", len(got.batch))
+		}
+		if got.err != sendErr {
+			t.Fatalf("expected sendErr, got %v", got.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for ErrorHandler to be invoked")
+	}
+
+	mu.Lock()
+	gotAttempts := attempts
+	mu.Unlock()
+	if gotAttempts != 3 {
+		t.Fatalf("expected 3 attempts (MaxAttempts), got %d", gotAttempts)
+	}
+}
+
+func TestRequeueOnExhaustion(t *testing.T) {
+	mgr := NewManager(Config{
+		FlushAfter:  time.Hour,
+		FlushLength: 1,
+		MaxAttempts: 1,
+		Requeue:     true,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			return errors.New("boom")
+		},
+	})
+
+	mgr.Add("dev-a", "x")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mgr.typed.mu.Lock()
+		b, exists := mgr.typed.buffers["dev-a"]
+		mgr.typed.mu.Unlock()
+		if exists {
+			b.mu.Lock()
+			n := len(b.items)
+			b.mu.Unlock()
+			if n == 1 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the failed item to be requeued")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFlushLengthTriggersBeforeFlushAfter(t *testing.T) {
+	var mu sync.Mutex
+	var sent []interface{}
+	done := make(chan struct{})
+
+	mgr := NewManager(Config{
+		FlushAfter:  time.Hour, // long enough that only FlushLength can trigger a flush
+		FlushLength: 3,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			mu.Lock()
+			sent = append(sent, batch...)
+			mu.Unlock()
+			close(done)
+			return nil
+		},
+	})
+
+	mgr.Add("dev-a", 1)
+	mgr.Add("dev-a", 2)
+	mgr.Add("dev-a", 3) // hits FlushLength and should flush immediately
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the size-triggered flush")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 items flushed, got %d", len(sent))
+	}
+}
+
+func TestOverflowAndEviction(t *testing.T) {
+	mgr := NewManager(Config{
+		FlushAfter:             time.Hour,
+		MaxBufferedItemsPerKey: 2,
+		OverflowPolicy:         DropOldest,
+		MaxKeys:                1,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			return nil
+		},
+	})
+
+	if action := mgr.Add("dev-a", 1); action != NoOverflow {
+		t.Fatalf("expected NoOverflow, got %v", action)
+	}
+	if action := mgr.Add("dev-a", 2); action != NoOverflow {
+		t.Fatalf("expected NoOverflow, got %v", action)
+	}
+	if action := mgr.Add("dev-a", 3); action != OverflowDroppedOldest {
+		t.Fatalf("expected OverflowDroppedOldest, got %v", action)
+	}
+
+	// MaxKeys is 1, so adding a second key must evict dev-a's buffer.
+	mgr.Add("dev-b", 1)
+
+	mgr.typed.mu.Lock()
+	_, aExists := mgr.typed.buffers["dev-a"]
+	_, bExists := mgr.typed.buffers["dev-b"]
+	mgr.typed.mu.Unlock()
+	if aExists {
+		t.Fatalf("expected dev-a to be evicted once MaxKeys was exceeded")
+	}
+	if !bExists {
+		t.Fatalf("expected dev-b to have a buffer")
+	}
+}
+
+// TestConcurrentAddWithMaxKeysEvictionDoesNotDeadlock guards against a
+// lock-order inversion between Add (which locks a buffer's mu, then calls
+// addTotal/overLimit) and evictLRULocked (which holds the Manager's mu while
+// locking buffers' mu in turn). With MaxKeys set and concurrent Add calls
+// across many keys, those two orders used to collide in an AB-BA deadlock.
+func TestConcurrentAddWithMaxKeysEvictionDoesNotDeadlock(t *testing.T) {
+	mgr := NewManager(Config{
+		FlushAfter: time.Hour,
+		MaxKeys:    2,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			return nil
+		},
+	})
+
+	const goroutines = 16
+	const keys = 10
+	const itemsPerGoroutine = 50
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < itemsPerGoroutine; i++ {
+				key := fmt.Sprintf("dev-%02d", (g+i)%keys)
+				mgr.Add(key, i)
+			}
+		}(g)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Add calls deadlocked under concurrent MaxKeys eviction")
+	}
 }
@@ -0,0 +1,608 @@
+// Generic Manager
+// TypedManager[T] batches and debounces values of a concrete type T instead
+// of interface{}, so callers get compile-time type safety and Add avoids
+// boxing T into an interface{} on every call. It holds the only locking
+// implementation in this package; Manager (interface{}-based) is a thin
+// wrapper around TypedManager[interface{}] kept for backward compatibility,
+// so the two never drift out of sync. New code that knows its item type
+// should prefer TypedManager[T] directly.
+
+package debounce
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TypedManager[T any] struct {
+	cfg     TypedConfig[T]
+	buffers map[string]*typedBuffer[T]
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	ctx     context.Context
+	closed  bool
+	// totalItems is atomic rather than guarded by mu because addTotal and
+	// overLimit are called from Add while b.mu (a buffer's lock) is held;
+	// m.mu is also taken, in the opposite order, by evictLRULocked while
+	// scanning buffers for the LRU key. Locking mu here too would create an
+	// AB-BA lock-order inversion between the two paths under concurrent
+	// Add calls across keys once MaxKeys eviction is in play.
+	totalItems  atomic.Int64
+	stopJanitor context.CancelFunc
+	stats       map[string]*KeyStats
+}
+
+type TypedConfig[T any] struct {
+	FlushAfter                time.Duration
+	MinIntervalBetweenFlushes time.Duration
+	SendFunc                  func(ctx context.Context, key string, batch []T) error
+
+	DrainTimeout time.Duration
+
+	ErrorHandler      func(key string, batch []T, err error) error
+	MaxAttempts       int
+	RetryBackoff      time.Duration
+	BackoffMultiplier float64
+	BackoffJitter     float64
+
+	Requeue          bool
+	MaxRequeuedItems int
+
+	FlushLength int
+
+	MaxKeys      int
+	IdleEviction time.Duration
+
+	MaxBufferedItemsPerKey int
+	MaxBufferedItemsTotal  int
+	OverflowPolicy         OverflowPolicy
+
+	Hooks   Hooks
+	Metrics MetricsRecorder
+}
+
+type typedBuffer[T any] struct {
+	mu             sync.Mutex
+	items          []T
+	timer          *time.Timer
+	lastFlushTime  time.Time
+	lastAccessTime time.Time
+	flushScheduled bool
+	evicted        bool
+}
+
+func NewTypedManager[T any](cfg TypedConfig[T]) *TypedManager[T] {
+	return &TypedManager[T]{
+		cfg:     cfg,
+		buffers: make(map[string]*typedBuffer[T]),
+		stats:   make(map[string]*KeyStats),
+		ctx:     context.Background(),
+	}
+}
+
+// Run starts the Manager's lifecycle: ctx is propagated to every SendFunc
+// call until the Manager is closed, and the idle-eviction janitor (if
+// IdleEviction is set) starts running. Run does not block; callers
+// typically defer a call to Close for graceful shutdown.
+func (m *TypedManager[T]) Run(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	m.ctx = ctx
+	janitorCtx, cancel := context.WithCancel(ctx)
+	m.stopJanitor = cancel
+	m.mu.Unlock()
+
+	if m.cfg.IdleEviction > 0 {
+		go m.runJanitor(janitorCtx)
+	} else {
+		cancel()
+	}
+	return nil
+}
+
+// Close stops all pending flush timers, flushes every non-empty buffer
+// synchronously, and waits for outstanding SendFunc calls, including retries
+// already scheduled, to finish. It
+// returns once draining completes or ctx (bounded by DrainTimeout, if set)
+// expires, whichever comes first.
+func (m *TypedManager[T]) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	if m.stopJanitor != nil {
+		m.stopJanitor()
+	}
+	buffers := make(map[string]*typedBuffer[T], len(m.buffers))
+	for key, b := range m.buffers {
+		buffers[key] = b
+	}
+	m.mu.Unlock()
+
+	drainCtx := ctx
+	if m.cfg.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(ctx, m.cfg.DrainTimeout)
+		defer cancel()
+	}
+
+	for key, b := range buffers {
+		b.mu.Lock()
+		if b.timer != nil {
+			b.timer.Stop()
+		}
+		batch := b.items
+		b.items = nil
+		b.flushScheduled = false
+		if len(batch) > 0 {
+			b.lastFlushTime = time.Now()
+		}
+		b.mu.Unlock()
+		m.addTotal(-len(batch))
+		m.setBuffered(key, 0)
+
+		if len(batch) > 0 {
+			m.wg.Add(1)
+			go func(key string, batch []T) {
+				defer m.wg.Done()
+				m.onFlushStart(key, len(batch))
+				m.send(drainCtx, key, batch, 1)
+			}(key, batch)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-drainCtx.Done():
+		return drainCtx.Err()
+	}
+}
+
+func (m *TypedManager[T]) context() context.Context {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ctx
+}
+
+func (m *TypedManager[T]) addTotal(delta int) {
+	m.totalItems.Add(int64(delta))
+}
+
+// statsFor returns key's KeyStats, creating it on first use. The caller
+// must hold m.mu.
+func (m *TypedManager[T]) statsFor(key string) *KeyStats {
+	s, ok := m.stats[key]
+	if !ok {
+		s = &KeyStats{}
+		m.stats[key] = s
+	}
+	return s
+}
+
+func (m *TypedManager[T]) setBuffered(key string, n int) {
+	m.mu.Lock()
+	m.statsFor(key).Buffered = n
+	m.mu.Unlock()
+}
+
+func (m *TypedManager[T]) recordFlushSuccess(key string, n int) {
+	m.mu.Lock()
+	s := m.statsFor(key)
+	s.FlushedBatches++
+	s.FlushedItems += int64(n)
+	s.LastFlushTime = time.Now()
+	m.mu.Unlock()
+}
+
+// onAdd reports the key's buffered count to Hooks.OnAdd and Metrics after
+// Add buffers an item.
+func (m *TypedManager[T]) onAdd(key string, count int) {
+	m.setBuffered(key, count)
+	if m.cfg.Hooks.OnAdd != nil {
+		m.cfg.Hooks.OnAdd(key, count)
+	}
+	if m.cfg.Metrics != nil {
+		m.cfg.Metrics.IncCounter("debounce_add_total", map[string]string{"key": key})
+		m.cfg.Metrics.SetGauge("debounce_buffered_items", float64(count), map[string]string{"key": key})
+	}
+}
+
+func (m *TypedManager[T]) onFlushScheduled(key string, in time.Duration) {
+	if m.cfg.Hooks.OnFlushScheduled != nil {
+		m.cfg.Hooks.OnFlushScheduled(key, in)
+	}
+}
+
+func (m *TypedManager[T]) onFlushStart(key string, batchSize int) {
+	if m.cfg.Hooks.OnFlushStart != nil {
+		m.cfg.Hooks.OnFlushStart(key, batchSize)
+	}
+	if m.cfg.Metrics != nil {
+		m.cfg.Metrics.IncCounter("debounce_flush_started_total", map[string]string{"key": key})
+	}
+}
+
+func (m *TypedManager[T]) onFlushDone(key string, batchSize int, dur time.Duration, err error) {
+	if m.cfg.Hooks.OnFlushDone != nil {
+		m.cfg.Hooks.OnFlushDone(key, batchSize, dur, err)
+	}
+	if m.cfg.Metrics != nil {
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		m.cfg.Metrics.IncCounter("debounce_flush_done_total", map[string]string{"key": key, "status": status})
+		m.cfg.Metrics.ObserveHistogram("debounce_flush_duration_seconds", dur.Seconds(), map[string]string{"key": key})
+	}
+}
+
+func (m *TypedManager[T]) onRateLimited(key string, waitFor time.Duration) {
+	if m.cfg.Hooks.OnRateLimited != nil {
+		m.cfg.Hooks.OnRateLimited(key, waitFor)
+	}
+	if m.cfg.Metrics != nil {
+		m.cfg.Metrics.IncCounter("debounce_rate_limited_total", map[string]string{"key": key})
+	}
+}
+
+// onEvictLocked reports key's eviction to Hooks.OnEvict and Metrics and
+// zeroes its buffered count. The caller must hold m.mu.
+func (m *TypedManager[T]) onEvictLocked(key string) {
+	if s, ok := m.stats[key]; ok {
+		s.Buffered = 0
+	}
+	if m.cfg.Hooks.OnEvict != nil {
+		m.cfg.Hooks.OnEvict(key)
+	}
+	if m.cfg.Metrics != nil {
+		m.cfg.Metrics.IncCounter("debounce_evicted_total", map[string]string{"key": key})
+	}
+}
+
+// Stats returns a snapshot of KeyStats for every key this TypedManager has
+// ever seen. The returned map is a copy, safe to read without further
+// locking.
+func (m *TypedManager[T]) Stats() map[string]KeyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]KeyStats, len(m.stats))
+	for key, s := range m.stats {
+		out[key] = *s
+	}
+	return out
+}
+
+// overLimit reports whether key's buffer (already locked by the caller) is
+// at or beyond MaxBufferedItemsPerKey or MaxBufferedItemsTotal.
+func (m *TypedManager[T]) overLimit(b *typedBuffer[T]) bool {
+	if m.cfg.MaxBufferedItemsPerKey > 0 && len(b.items) >= m.cfg.MaxBufferedItemsPerKey {
+		return true
+	}
+	if m.cfg.MaxBufferedItemsTotal > 0 && m.totalItems.Load() >= int64(m.cfg.MaxBufferedItemsTotal) {
+		return true
+	}
+	return false
+}
+
+// Add buffers item under key, creating the key's buffer on first use. It
+// returns the OverflowAction taken, if MaxBufferedItemsPerKey or
+// MaxBufferedItemsTotal was reached.
+func (m *TypedManager[T]) Add(key string, item T) OverflowAction {
+retry:
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return NoOverflow
+		}
+		b, exists := m.buffers[key]
+		if !exists {
+			if m.cfg.MaxKeys > 0 && len(m.buffers) >= m.cfg.MaxKeys {
+				m.evictLRULocked()
+			}
+			b = &typedBuffer[T]{}
+			m.buffers[key] = b
+		}
+		m.mu.Unlock()
+
+		b.mu.Lock()
+		if b.evicted {
+			b.mu.Unlock()
+			continue retry
+		}
+		b.lastAccessTime = time.Now()
+
+		action := NoOverflow
+	overflowLoop:
+		for m.overLimit(b) {
+			switch m.cfg.OverflowPolicy {
+			case DropNewest:
+				b.mu.Unlock()
+				return OverflowDroppedNewest
+			case BlockAdd:
+				b.mu.Unlock()
+				time.Sleep(blockAddPollInterval)
+				b.mu.Lock()
+				if b.evicted {
+					b.mu.Unlock()
+					continue retry
+				}
+				action = OverflowBlocked
+			case FlushImmediately:
+				action = OverflowFlushed
+				break overflowLoop
+			default: // DropOldest
+				if len(b.items) == 0 {
+					break overflowLoop
+				}
+				b.items = b.items[1:]
+				m.addTotal(-1)
+				action = OverflowDroppedOldest
+			}
+		}
+
+		b.items = append(b.items, item)
+		m.addTotal(1)
+		scheduledNow := false
+		if !b.flushScheduled {
+			b.flushScheduled = true
+			b.timer = time.AfterFunc(m.cfg.FlushAfter, func() {
+				m.flush(key)
+			})
+			scheduledNow = true
+		}
+		lengthTriggered := m.cfg.FlushLength > 0 && len(b.items) >= m.cfg.FlushLength
+		flushNow := lengthTriggered || action == OverflowFlushed
+		if flushNow && b.timer != nil {
+			b.timer.Stop()
+		}
+		bufferedCount := len(b.items)
+		b.mu.Unlock()
+
+		m.onAdd(key, bufferedCount)
+		if scheduledNow {
+			m.onFlushScheduled(key, m.cfg.FlushAfter)
+		}
+
+		if flushNow {
+			m.flush(key)
+		}
+		return action
+	}
+}
+
+func (m *TypedManager[T]) flush(key string) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	b, exists := m.buffers[key]
+	if !exists {
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	b.mu.Lock()
+	now := time.Now()
+	if !b.lastFlushTime.IsZero() && now.Sub(b.lastFlushTime) < m.cfg.MinIntervalBetweenFlushes {
+		delay := m.cfg.MinIntervalBetweenFlushes - now.Sub(b.lastFlushTime)
+		b.timer = time.AfterFunc(delay, func() {
+			m.flush(key)
+		})
+		b.mu.Unlock()
+		return
+	}
+
+	batch := b.items
+	b.items = nil
+	b.lastFlushTime = now
+	b.flushScheduled = false
+	b.mu.Unlock()
+
+	m.addTotal(-len(batch))
+	m.setBuffered(key, 0)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.onFlushStart(key, len(batch))
+		m.send(m.context(), key, batch, 1)
+	}()
+}
+
+// send invokes SendFunc for batch and, on failure, reschedules it with
+// backoff until MaxAttempts is reached, at which point it requeues the
+// batch (if configured) and hands it to ErrorHandler.
+func (m *TypedManager[T]) send(ctx context.Context, key string, batch []T, attempt int) {
+	start := time.Now()
+	err := m.cfg.SendFunc(ctx, key, batch)
+	dur := time.Since(start)
+	if err == nil {
+		m.recordFlushSuccess(key, len(batch))
+	}
+	m.onFlushDone(key, len(batch), dur, err)
+	if err == nil {
+		return
+	}
+
+	maxAttempts := m.cfg.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if attempt < maxAttempts {
+		m.wg.Add(1)
+		time.AfterFunc(m.retryDelay(key, attempt), func() {
+			defer m.wg.Done()
+			m.send(ctx, key, batch, attempt+1)
+		})
+		return
+	}
+
+	if m.cfg.Requeue {
+		m.requeue(key, batch)
+	}
+	if m.cfg.ErrorHandler != nil {
+		m.cfg.ErrorHandler(key, batch, err)
+	}
+}
+
+// retryDelay computes the backoff for the given attempt and stretches it, if
+// necessary, so the retry still honors MinIntervalBetweenFlushes for key.
+func (m *TypedManager[T]) retryDelay(key string, attempt int) time.Duration {
+	delay := float64(m.cfg.RetryBackoff)
+	if m.cfg.BackoffMultiplier > 1 {
+		delay *= math.Pow(m.cfg.BackoffMultiplier, float64(attempt-1))
+	}
+	if m.cfg.BackoffJitter > 0 {
+		jitter := delay * m.cfg.BackoffJitter
+		delay += (rand.Float64()*2 - 1) * jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	backoff := time.Duration(delay)
+
+	m.mu.Lock()
+	b, exists := m.buffers[key]
+	m.mu.Unlock()
+	if !exists {
+		return backoff
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if since := time.Since(b.lastFlushTime); since < m.cfg.MinIntervalBetweenFlushes {
+		if wait := m.cfg.MinIntervalBetweenFlushes - since; wait > backoff {
+			m.onRateLimited(key, wait)
+			return wait
+		}
+	}
+	return backoff
+}
+
+// requeue prepends batch back into key's buffer so it is included in the
+// next flush, trimming to MaxRequeuedItems (keeping the oldest items, i.e.
+// those that have been waiting longest) if set.
+func (m *TypedManager[T]) requeue(key string, batch []T) {
+	if m.cfg.MaxRequeuedItems > 0 && len(batch) > m.cfg.MaxRequeuedItems {
+		batch = batch[:m.cfg.MaxRequeuedItems]
+	}
+
+	m.mu.Lock()
+	b, exists := m.buffers[key]
+	if !exists {
+		b = &typedBuffer[T]{}
+		m.buffers[key] = b
+	}
+	m.mu.Unlock()
+
+	b.mu.Lock()
+	b.items = append(append([]T{}, batch...), b.items...)
+	m.addTotal(len(batch))
+	if !b.flushScheduled {
+		b.flushScheduled = true
+		b.timer = time.AfterFunc(m.cfg.FlushAfter, func() {
+			m.flush(key)
+		})
+	}
+	bufferedCount := len(b.items)
+	b.mu.Unlock()
+	m.setBuffered(key, bufferedCount)
+}
+
+// evictLRULocked removes the least-recently-used key's buffer, flushing it
+// first if it has buffered items. The caller must hold m.mu.
+func (m *TypedManager[T]) evictLRULocked() {
+	var lruKey string
+	var lruTime time.Time
+	found := false
+	for key, b := range m.buffers {
+		b.mu.Lock()
+		t := b.lastAccessTime
+		b.mu.Unlock()
+		if !found || t.Before(lruTime) {
+			lruKey, lruTime, found = key, t, true
+		}
+	}
+	if !found {
+		return
+	}
+	b := m.buffers[lruKey]
+	delete(m.buffers, lruKey)
+	m.onEvictLocked(lruKey)
+
+	b.mu.Lock()
+	b.evicted = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	batch := b.items
+	b.items = nil
+	b.flushScheduled = false
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	m.addTotal(-len(batch))
+	m.wg.Add(1)
+	ctx := m.ctx
+	go func() {
+		defer m.wg.Done()
+		m.onFlushStart(lruKey, len(batch))
+		m.send(ctx, lruKey, batch, 1)
+	}()
+}
+
+// runJanitor periodically evicts buffers that have been empty for longer
+// than IdleEviction, until ctx is done.
+func (m *TypedManager[T]) runJanitor(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.IdleEviction)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.evictIdle()
+		}
+	}
+}
+
+func (m *TypedManager[T]) evictIdle() {
+	cutoff := time.Now().Add(-m.cfg.IdleEviction)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, b := range m.buffers {
+		b.mu.Lock()
+		idle := len(b.items) == 0 && !b.lastFlushTime.IsZero() && b.lastFlushTime.Before(cutoff)
+		if idle {
+			b.evicted = true
+		}
+		b.mu.Unlock()
+		if idle {
+			delete(m.buffers, key)
+			m.onEvictLocked(key)
+		}
+	}
+}
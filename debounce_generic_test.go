@@ -0,0 +1,67 @@
+package debounce
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTypedManagerBuffersByKey(t *testing.T) {
+	var mu sync.Mutex
+	sent := make(map[string][]int)
+	done := make(chan struct{})
+
+	mgr := NewTypedManager(TypedConfig[int]{
+		FlushAfter:  time.Hour,
+		FlushLength: 2,
+		SendFunc: func(ctx context.Context, key string, batch []int) error {
+			mu.Lock()
+			sent[key] = append(sent[key], batch...)
+			mu.Unlock()
+			close(done)
+			return nil
+		},
+	})
+
+	mgr.Add("dev-a", 1)
+	mgr.Add("dev-a", 2) // hits FlushLength
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the size-triggered flush")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := sent["dev-a"]; len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("expected [1 2], got %v", got)
+	}
+}
+
+func BenchmarkAddInterfaceManager(b *testing.B) {
+	mgr := NewManager(Config{
+		FlushAfter: time.Hour,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			return nil
+		},
+	})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mgr.Add("dev-a", i)
+	}
+}
+
+func BenchmarkAddTypedManager(b *testing.B) {
+	mgr := NewTypedManager(TypedConfig[int]{
+		FlushAfter: time.Hour,
+		SendFunc: func(ctx context.Context, key string, batch []int) error {
+			return nil
+		},
+	})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mgr.Add("dev-a", i)
+	}
+}
@@ -0,0 +1,83 @@
+package debounce
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHooksAndStatsOnSuccessfulFlush(t *testing.T) {
+	var mu sync.Mutex
+	var addCount, flushStart, flushDone int
+	done := make(chan struct{})
+
+	mgr := NewManager(Config{
+		FlushAfter:  time.Hour,
+		FlushLength: 2,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			return nil
+		},
+		Hooks: Hooks{
+			OnAdd: func(key string, bufferedCount int) {
+				mu.Lock()
+				addCount++
+				mu.Unlock()
+			},
+			OnFlushStart: func(key string, batchSize int) {
+				mu.Lock()
+				flushStart++
+				mu.Unlock()
+			},
+			OnFlushDone: func(key string, batchSize int, dur time.Duration, err error) {
+				mu.Lock()
+				flushDone++
+				mu.Unlock()
+				close(done)
+			},
+		},
+	})
+
+	mgr.Add("dev-a", 1)
+	mgr.Add("dev-a", 2) // hits FlushLength
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for OnFlushDone")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if addCount != 2 {
+		t.Fatalf("expected 2 OnAdd calls, got %d", addCount)
+	}
+	if flushStart != 1 || flushDone != 1 {
+		t.Fatalf("expected 1 OnFlushStart/OnFlushDone, got %d/%d", flushStart, flushDone)
+	}
+
+	stats := mgr.Stats()["dev-a"]
+	if stats.FlushedBatches != 1 || stats.FlushedItems != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.Buffered != 0 {
+		t.Fatalf("expected buffered count 0 after flush, got %d", stats.Buffered)
+	}
+}
+
+func TestStatsTracksBufferedCount(t *testing.T) {
+	mgr := NewManager(Config{
+		FlushAfter: time.Hour,
+		SendFunc: func(ctx context.Context, key string, batch []interface{}) error {
+			return nil
+		},
+	})
+
+	mgr.Add("dev-a", 1)
+	mgr.Add("dev-a", 2)
+
+	stats := mgr.Stats()["dev-a"]
+	if stats.Buffered != 2 {
+		t.Fatalf("expected buffered count 2, got %d", stats.Buffered)
+	}
+}
@@ -0,0 +1,31 @@
+package flusher
+
+import "context"
+
+// Batch is one flushed batch, captured by ChannelFlusher.
+type Batch struct {
+	Key   string
+	Items []interface{}
+}
+
+// ChannelFlusher sends each flushed batch on a channel instead of an
+// external sink. It is mainly useful in tests that want to assert on what
+// debounce.Manager flushed without standing up an HTTP server or file.
+type ChannelFlusher struct {
+	C chan Batch
+}
+
+// NewChannelFlusher returns a ChannelFlusher whose channel has the given
+// buffer size.
+func NewChannelFlusher(buffer int) *ChannelFlusher {
+	return &ChannelFlusher{C: make(chan Batch, buffer)}
+}
+
+func (f *ChannelFlusher) Flush(ctx context.Context, key string, batch []interface{}) error {
+	select {
+	case f.C <- Batch{Key: key, Items: batch}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
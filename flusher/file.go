@@ -0,0 +1,124 @@
+package flusher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileFlusher appends each flushed batch as newline-delimited JSON (one line
+// per item) to a file, rotating to a new numbered file once the current one
+// reaches MaxBytes.
+type FileFlusher struct {
+	// Path is the base file path, e.g. "batches.ndjson". Rotated files are
+	// suffixed with ".1", ".2", and so on, oldest last.
+	Path string
+	// MaxBytes rotates the file once its size would exceed this value.
+	// Zero disables rotation.
+	MaxBytes int64
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func (f *FileFlusher) Flush(ctx context.Context, key string, batch []interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.ensureOpenLocked(); err != nil {
+		return err
+	}
+
+	for _, item := range batch {
+		line, err := json.Marshal(struct {
+			Key  string      `json:"key"`
+			Item interface{} `json:"item"`
+		}{Key: key, Item: item})
+		if err != nil {
+			return fmt.Errorf("flusher: marshal item: %w", err)
+		}
+		line = append(line, '\n')
+
+		if f.MaxBytes > 0 && f.size > 0 && f.size+int64(len(line)) > f.MaxBytes {
+			if err := f.rotateLocked(); err != nil {
+				return err
+			}
+		}
+
+		n, err := f.f.Write(line)
+		if err != nil {
+			return fmt.Errorf("flusher: write item: %w", err)
+		}
+		f.size += int64(n)
+	}
+	return nil
+}
+
+func (f *FileFlusher) ensureOpenLocked() error {
+	if f.f != nil {
+		return nil
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("flusher: open %s: %w", f.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("flusher: stat %s: %w", f.Path, err)
+	}
+	f.f = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *FileFlusher) rotateLocked() error {
+	if err := f.f.Close(); err != nil {
+		return fmt.Errorf("flusher: close %s for rotation: %w", f.Path, err)
+	}
+	if err := f.cascadeRotateLocked(); err != nil {
+		return err
+	}
+	f.f = nil
+	f.size = 0
+	return f.ensureOpenLocked()
+}
+
+// cascadeRotateLocked shifts .N-1 to .N, from the highest existing backup
+// down to .1, before renaming the active file to .1, so an existing backup
+// is never overwritten by a later rotation.
+func (f *FileFlusher) cascadeRotateLocked() error {
+	n := 0
+	for {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", f.Path, n+1)); err != nil {
+			break
+		}
+		n++
+	}
+	for i := n; i >= 1; i-- {
+		oldName := fmt.Sprintf("%s.%d", f.Path, i)
+		newName := fmt.Sprintf("%s.%d", f.Path, i+1)
+		if err := os.Rename(oldName, newName); err != nil {
+			return fmt.Errorf("flusher: rotate %s: %w", oldName, err)
+		}
+	}
+	if err := os.Rename(f.Path, f.Path+".1"); err != nil {
+		return fmt.Errorf("flusher: rotate %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// Close closes the underlying file, if open.
+func (f *FileFlusher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
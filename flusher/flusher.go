@@ -0,0 +1,14 @@
+// Package flusher provides ready-made Flush sinks for debounce.Manager:
+// an HTTP JSON POST flusher, a newline-delimited JSON file flusher, a
+// channel flusher for tests, and a MultiFlusher that fans a batch out to
+// several sinks.
+package flusher
+
+import "context"
+
+// Flusher matches debounce.Flusher's method set structurally, so any type
+// in this package can be assigned directly to a debounce.Config.Flusher
+// field without importing the debounce package here.
+type Flusher interface {
+	Flush(ctx context.Context, key string, batch []interface{}) error
+}
@@ -0,0 +1,75 @@
+package flusher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChannelFlusherDeliversBatch(t *testing.T) {
+	f := NewChannelFlusher(1)
+	if err := f.Flush(context.Background(), "dev-a", []interface{}{1, 2}); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	select {
+	case b := <-f.C:
+		if b.Key != "dev-a" || len(b.Items) != 2 {
+			t.Fatalf("unexpected batch: %+v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for batch on channel")
+	}
+}
+
+type errFlusher struct{ err error }
+
+func (f errFlusher) Flush(ctx context.Context, key string, batch []interface{}) error {
+	return f.err
+}
+
+func TestMultiFlusherCombinesErrors(t *testing.T) {
+	ch := NewChannelFlusher(1)
+	failing := errFlusher{err: errors.New("boom")}
+
+	m := &MultiFlusher{Sinks: []Flusher{ch, failing}}
+	err := m.Flush(context.Background(), "dev-a", []interface{}{1})
+	if err == nil {
+		t.Fatal("expected an error from the failing sink")
+	}
+
+	select {
+	case <-ch.C:
+	default:
+		t.Fatal("expected the channel sink to still receive the batch")
+	}
+}
+
+func TestFileFlusherCascadesRotationsWithoutOverwriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batches.ndjson")
+	f := &FileFlusher{Path: path, MaxBytes: 1}
+	defer f.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := f.Flush(context.Background(), "dev-a", []interface{}{i}); err != nil {
+			t.Fatalf("Flush %d: %v", i, err)
+		}
+	}
+
+	for _, suffix := range []string{"", ".1", ".2"} {
+		if _, err := os.Stat(path + suffix); err != nil {
+			t.Fatalf("expected %s%s to exist: %v", path, suffix, err)
+		}
+	}
+
+	oldest, err := os.ReadFile(path + ".2")
+	if err != nil {
+		t.Fatalf("reading oldest rotation: %v", err)
+	}
+	if len(oldest) == 0 {
+		t.Fatal("oldest rotation is empty; first batch's content was lost")
+	}
+}
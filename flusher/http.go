@@ -0,0 +1,93 @@
+package flusher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// HTTPFlusher POSTs each flushed batch as a JSON array to an HTTP endpoint.
+type HTTPFlusher struct {
+	// Endpoint is the request URL. It may contain a text/template
+	// reference to {{.Key}}, e.g. "https://example.com/ingest/{{.Key}}".
+	Endpoint string
+	// Headers are set on every request, after Content-Type and (if Gzip)
+	// Content-Encoding.
+	Headers map[string]string
+	// Gzip compresses the request body when set.
+	Gzip bool
+	// Client is used to send requests. http.DefaultClient is used if nil.
+	Client *http.Client
+}
+
+func (f *HTTPFlusher) Flush(ctx context.Context, key string, batch []interface{}) error {
+	url, err := f.renderEndpoint(key)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("flusher: marshal batch: %w", err)
+	}
+
+	var reqBody io.Reader = bytes.NewReader(body)
+	if f.Gzip {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(body); err != nil {
+			return fmt.Errorf("flusher: gzip batch: %w", err)
+		}
+		if err := gw.Close(); err != nil {
+			return fmt.Errorf("flusher: gzip batch: %w", err)
+		}
+		reqBody = &buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("flusher: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range f.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("flusher: send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("flusher: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (f *HTTPFlusher) renderEndpoint(key string) (string, error) {
+	if !strings.Contains(f.Endpoint, "{{") {
+		return f.Endpoint, nil
+	}
+	tmpl, err := template.New("endpoint").Parse(f.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("flusher: parse endpoint template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Key string }{Key: key}); err != nil {
+		return "", fmt.Errorf("flusher: render endpoint template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,26 @@
+package flusher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MultiFlusher fans a single flushed batch out to several sinks. All sinks
+// are tried even if one fails; their errors are combined into one error.
+type MultiFlusher struct {
+	Sinks []Flusher
+}
+
+func (f *MultiFlusher) Flush(ctx context.Context, key string, batch []interface{}) error {
+	var errs []string
+	for i, sink := range f.Sinks {
+		if err := sink.Flush(ctx, key, batch); err != nil {
+			errs = append(errs, fmt.Sprintf("sink %d: %v", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("flusher: %d of %d sinks failed: %s", len(errs), len(f.Sinks), strings.Join(errs, "; "))
+	}
+	return nil
+}